@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+const (
+	FixedTag   = 0xA         // Fixed tag
+	Magic1     = 0x5a4b3c2d  // Magic value 1
+	Magic2     = 0xa5b4c3d2  // Magic value 2
+	Length     = 256 * 1024  // ADV size in bytes
+	DataLength = Length - 44 // Available space for data
+)
+
+type ADV struct {
+	Tags map[uint8][]byte
+	mu   sync.Mutex
+}
+
+// NewADV initializes ADV. If the device does not contain a valid Magic1, an empty ADV is returned.
+func NewADV(r io.Reader) (*ADV, error) {
+	a := &ADV{
+		Tags: make(map[uint8][]byte),
+	}
+
+	if r == nil {
+		return a, nil
+	}
+
+	buf := make([]byte, Length)
+	_, err := io.ReadFull(r, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = a.unmarshal(buf); err != nil {
+		log.Printf("ADV does not contain a valid Magic1: initializing a new ADV.")
+		return &ADV{Tags: make(map[uint8][]byte)}, nil
+	}
+
+	return a, nil
+}
+
+// unmarshal loads data from the buffer into the ADV structure
+func (a *ADV) unmarshal(buf []byte) error {
+	tags, err := parseSlot(buf)
+	if err != nil {
+		return err
+	}
+
+	a.Tags = tags
+	return nil
+}
+
+// parseSlot validates a single 256 KiB ADV slot (magic markers and
+// checksum) and decodes its tag|size|value TLV run. It leaves buf
+// untouched and is used both by the primary load path and by
+// LoadWithFallback to validate the primary and backup slots
+// independently during recovery.
+//
+// Layout: magic1 (4 bytes), tag count (4 bytes), the TLV run, a 32-byte
+// checksum and magic2 (4 bytes). The tag count is the run's terminator:
+// without it, the TLV walk can't tell real tags apart from the
+// zero-padding marshal always leaves after them, and would decode that
+// padding as a phantom tag 0.
+func parseSlot(buf []byte) (map[uint8][]byte, error) {
+	magic1 := binary.BigEndian.Uint32(buf[:4])
+	if magic1 != Magic1 {
+		return nil, fmt.Errorf("adv: incorrect magic1 value: %x", magic1)
+	}
+
+	magic2 := binary.BigEndian.Uint32(buf[len(buf)-4:])
+	if magic2 != Magic2 {
+		return nil, fmt.Errorf("adv: incorrect magic2 value: %x", magic2)
+	}
+
+	checksum := buf[len(buf)-36 : len(buf)-4]
+	verifyBuf := make([]byte, len(buf))
+	copy(verifyBuf, buf)
+	copy(verifyBuf[len(verifyBuf)-36:len(verifyBuf)-4], make([]byte, 32))
+
+	hash := sha256.Sum256(verifyBuf)
+	if !bytes.Equal(checksum, hash[:]) {
+		return nil, fmt.Errorf("adv: invalid checksum")
+	}
+
+	count := binary.BigEndian.Uint32(buf[4:8])
+
+	tags := make(map[uint8][]byte)
+	data := buf[8 : len(buf)-36]
+	for i := uint32(0); i < count; i++ {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("adv: tag count %d exceeds available data", count)
+		}
+
+		tag := uint8(binary.BigEndian.Uint32(data[0:4]))
+		size := binary.BigEndian.Uint32(data[4:8])
+
+		if len(data) < int(size)+8 {
+			return nil, fmt.Errorf("adv: value exceeds buffer limits")
+		}
+
+		value := data[8 : 8+size]
+		tags[tag] = value
+		data = data[8+size:]
+	}
+
+	return tags, nil
+}
+
+// SetTagBytes sets the tag value in byte format
+func (a *ADV) SetTagBytes(tag uint8, val []byte) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	size := 20 // magic and checksum
+	for _, v := range a.Tags {
+		size += len(v) + 8
+	}
+
+	if len(val)+size > DataLength {
+		return false
+	}
+
+	a.Tags[tag] = val
+	return true
+}
+
+// GetTagBytes returns the raw value stored under tag, if any.
+func (a *ADV) GetTagBytes(tag uint8) ([]byte, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	val, ok := a.Tags[tag]
+	return val, ok
+}
+
+// DeleteTag removes tag from the ADV. It reports whether the tag was present.
+func (a *ADV) DeleteTag(tag uint8) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.Tags[tag]; !ok {
+		return false
+	}
+
+	delete(a.Tags, tag)
+	return true
+}
+
+// marshal converts ADV data into a byte array
+func (a *ADV) marshal() ([]byte, error) {
+	buf := make([]byte, Length)
+	binary.BigEndian.PutUint32(buf[0:4], Magic1)
+	binary.BigEndian.PutUint32(buf[4:8], uint32(len(a.Tags)))
+	binary.BigEndian.PutUint32(buf[len(buf)-4:], Magic2)
+
+	data := buf[8 : len(buf)-36]
+	for tag, value := range a.Tags {
+		binary.BigEndian.PutUint32(data[0:4], uint32(tag))
+		binary.BigEndian.PutUint32(data[4:8], uint32(len(value)))
+		copy(data[8:8+len(value)], value)
+		data = data[8+len(value):]
+	}
+
+	hash := sha256.Sum256(buf)
+	copy(buf[len(buf)-36:len(buf)-4], hash[:])
+	return buf, nil
+}
+
+// loadADV opens devicePath and loads the ADV stored on it. If the device
+// cannot be opened, an empty ADV is returned so callers can still write a
+// fresh one.
+func loadADV(devicePath string) (*ADV, error) {
+	f, err := os.Open(devicePath)
+	if err != nil {
+		return &ADV{Tags: make(map[uint8][]byte)}, nil
+	}
+	defer f.Close()
+
+	return NewADV(f)
+}
+
+// openReadWrite opens devicePath for reading and writing, e.g. so callers
+// can both inspect its current contents and write a repaired copy back.
+func openReadWrite(devicePath string) (*os.File, error) {
+	return os.OpenFile(devicePath, os.O_RDWR, 0)
+}
+
+// WriteToDisk writes ADV data to disk
+func (a *ADV) WriteToDisk(devicePath string) error {
+	serialized, err := a.marshal()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(devicePath, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteAt(serialized, 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteAt(serialized, Length)
+	return err
+}
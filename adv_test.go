@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestNewADV_RoundTrip guards against a regression where unmarshal read
+// the checksum from the same buffer it then zeroed in place before
+// hashing, so the comparison was always against a zeroed checksum and
+// every previously-written ADV looked corrupt.
+func TestNewADV_RoundTrip(t *testing.T) {
+	want := &ADV{Tags: map[uint8][]byte{FixedTag: []byte("hello")}}
+	buf, err := want.marshal()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	got, err := NewADV(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatalf("NewADV: %v", err)
+	}
+
+	value, ok := got.GetTagBytes(FixedTag)
+	if !ok {
+		t.Fatalf("expected tag %d to round-trip, got tags: %+v", FixedTag, got.Tags)
+	}
+	if string(value) != "hello" {
+		t.Fatalf("unexpected tag value: %q", value)
+	}
+}
+
+// TestNewADV_NoPhantomTags guards against a regression where parseSlot
+// had no terminator for the TLV run and kept decoding past the real
+// tags into marshal's trailing zero-padding, surfacing it as a bogus
+// tag 0 on every device.
+func TestNewADV_NoPhantomTags(t *testing.T) {
+	want := &ADV{Tags: map[uint8][]byte{5: []byte("hello")}}
+	buf, err := want.marshal()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	got, err := NewADV(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatalf("NewADV: %v", err)
+	}
+	if len(got.Tags) != 1 {
+		t.Fatalf("expected exactly one tag, got: %+v", got.Tags)
+	}
+	if _, ok := got.Tags[0]; ok {
+		t.Fatalf("expected no phantom tag 0, got: %+v", got.Tags)
+	}
+}
+
+func TestNewADV_RejectsCorruptChecksum(t *testing.T) {
+	adv := &ADV{Tags: map[uint8][]byte{FixedTag: []byte("hello")}}
+	buf, err := adv.marshal()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	buf[len(buf)-10] ^= 0xff // corrupt a byte inside the checksum
+
+	got, err := NewADV(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatalf("NewADV: %v", err)
+	}
+	if len(got.Tags) != 0 {
+		t.Fatalf("expected a corrupt checksum to yield an empty ADV, got: %+v", got.Tags)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// MetaBackend abstracts reading and mutating META tags so the same CLI
+// code can target an offline device or a running Talos node. Callers
+// must Close it once done, so ResourceBackend can release its gRPC
+// connection.
+type MetaBackend interface {
+	io.Closer
+
+	// Load returns the current set of tags.
+	Load(ctx context.Context) (*ADV, error)
+	// WriteTag sets tag to val, growing or creating the backing store as
+	// needed.
+	WriteTag(ctx context.Context, tag uint8, val []byte) error
+	// DeleteTag removes tag. It returns an error if the tag is not set.
+	DeleteTag(ctx context.Context, tag uint8) error
+}
+
+// DeviceBackend implements MetaBackend against a raw META block device,
+// e.g. /dev/sda4.
+type DeviceBackend struct {
+	Path string
+}
+
+// Close is a no-op: DeviceBackend doesn't hold a connection open between
+// calls, it only opens the device for the duration of each one.
+func (b *DeviceBackend) Close() error {
+	return nil
+}
+
+func (b *DeviceBackend) Load(ctx context.Context) (*ADV, error) {
+	return loadADV(b.Path)
+}
+
+func (b *DeviceBackend) WriteTag(ctx context.Context, tag uint8, val []byte) error {
+	adv, err := loadADV(b.Path)
+	if err != nil {
+		return fmt.Errorf("error loading ADV: %w", err)
+	}
+
+	if !adv.SetTagBytes(tag, val) {
+		return fmt.Errorf("not enough space to write tag %d", tag)
+	}
+
+	return adv.WriteToDisk(b.Path)
+}
+
+func (b *DeviceBackend) DeleteTag(ctx context.Context, tag uint8) error {
+	adv, err := loadADV(b.Path)
+	if err != nil {
+		return fmt.Errorf("error loading ADV: %w", err)
+	}
+
+	if !adv.DeleteTag(tag) {
+		return fmt.Errorf("tag %d not found", tag)
+	}
+
+	return adv.WriteToDisk(b.Path)
+}
+
+// registerBackendFlags adds the flags shared by every subcommand that
+// needs a MetaBackend: -device for the offline path, -node/-talosconfig
+// to target a live cluster instead.
+func registerBackendFlags(fs *flag.FlagSet) (devicePath, node, talosconfig *string) {
+	devicePath = fs.String("device", "", "Path to the META device (e.g., /dev/sda4)")
+	node = fs.String("node", "", "Address of a running Talos node to target instead of -device")
+	talosconfig = fs.String("talosconfig", defaultTalosconfigPath(), "Path to a talosconfig file, used with -node")
+	return devicePath, node, talosconfig
+}
+
+// openBackend resolves -device/-node into a MetaBackend, dialing the node
+// over its gRPC resource API when -node is given.
+func openBackend(ctx context.Context, devicePath, node, talosconfigPath string) (MetaBackend, error) {
+	if node != "" {
+		return NewResourceBackend(ctx, node, talosconfigPath)
+	}
+
+	if devicePath == "" {
+		return nil, fmt.Errorf("one of -device or -node must be given")
+	}
+
+	return &DeviceBackend{Path: devicePath}, nil
+}
+
+// defaultTalosconfigPath mirrors talosctl's own default: $TALOSCONFIG, or
+// ~/.talos/config if unset.
+func defaultTalosconfigPath() string {
+	if p := os.Getenv("TALOSCONFIG"); p != "" {
+		return p
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".talos", "config")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/aenix-io/talos-meta-tool/tags"
+)
+
+// runDelete removes a tag from a META device or a running Talos node.
+func runDelete(args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	devicePath, node, talosconfig := registerBackendFlags(fs)
+	tag := fs.String("tag", "", "Tag to delete, by symbolic name (e.g. user-reserved-1) or numeric id")
+	force := fs.Bool("force", false, "Allow deleting a reserved or undocumented tag")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *tag == "" {
+		return fmt.Errorf("usage: delete (-device <META-device> | -node <addr>) -tag <name|id>")
+	}
+
+	tagID, err := tags.Parse(*tag)
+	if err != nil {
+		return err
+	}
+
+	if tags.Reserved(tagID) && !*force {
+		return fmt.Errorf("tag %d is reserved for Talos's own use; pass -force to delete it anyway", tagID)
+	}
+
+	ctx := context.Background()
+
+	backend, err := openBackend(ctx, *devicePath, *node, *talosconfig)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	if err := backend.DeleteTag(ctx, tagID); err != nil {
+		return fmt.Errorf("error deleting tag %d: %w", tagID, err)
+	}
+
+	fmt.Printf("Tag %d deleted.\n", tagID)
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runDump prints every tag stored on a META device or Talos node as a
+// single YAML document mapping tag id to its decoded value.
+func runDump(args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	devicePath, node, talosconfig := registerBackendFlags(fs)
+	raw := fs.Bool("raw", false, "Print tag values as hex instead of decoding them as YAML")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	backend, err := openBackend(ctx, *devicePath, *node, *talosconfig)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	adv, err := backend.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("error loading ADV: %w", err)
+	}
+
+	tags := make([]int, 0, len(adv.Tags))
+	for tag := range adv.Tags {
+		tags = append(tags, int(tag))
+	}
+	sort.Ints(tags)
+
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, tag := range tags {
+		value := adv.Tags[uint8(tag)]
+
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: fmt.Sprintf("%d", tag)}
+		doc.Content = append(doc.Content, key, decodeTagValue(value, *raw))
+	}
+
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	defer enc.Close()
+	return enc.Encode(doc)
+}
+
+// decodeTagValue renders a tag's raw bytes as a YAML node: the decoded
+// value when it parses as YAML, or a hex string otherwise (always hex
+// when raw is requested).
+func decodeTagValue(value []byte, raw bool) *yaml.Node {
+	if !raw {
+		var decoded yaml.Node
+		if err := yaml.Unmarshal(value, &decoded); err == nil && decoded.Kind == yaml.DocumentNode && len(decoded.Content) == 1 {
+			return decoded.Content[0]
+		}
+	}
+
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: hex.EncodeToString(value)}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestDecodeTagValue_UnwrapsDocumentNode guards against a regression
+// where decodeTagValue returned the yaml.Node produced by
+// yaml.Unmarshal directly; that node is a DocumentNode wrapping the
+// real content in Content[0], and embedding a DocumentNode as a mapping
+// value produces a YAML document that fails to re-encode.
+func TestDecodeTagValue_UnwrapsDocumentNode(t *testing.T) {
+	node := decodeTagValue([]byte("machine:\n  type: controlplane\n"), false)
+	if node.Kind == yaml.DocumentNode {
+		t.Fatalf("expected the document wrapper to be unwrapped, got kind %v", node.Kind)
+	}
+	if node.Kind != yaml.MappingNode {
+		t.Fatalf("expected a mapping node, got kind %v", node.Kind)
+	}
+
+	if _, err := yaml.Marshal(node); err != nil {
+		t.Fatalf("re-encoding the decoded node failed: %v", err)
+	}
+}
+
+func TestDecodeTagValue_FallsBackToHex(t *testing.T) {
+	node := decodeTagValue([]byte{0xde, 0xad, 0xbe, 0xef}, false)
+	if node.Kind != yaml.ScalarNode || node.Value != "deadbeef" {
+		t.Fatalf("expected a hex scalar fallback, got: %+v", node)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Envelope layout for an encrypted tag value:
+//
+//	1 byte   version
+//	16 bytes scrypt salt
+//	12 bytes AES-GCM nonce
+//	N bytes  ciphertext (includes the 16-byte GCM authentication tag)
+const (
+	encryptionVersion1 = 0x01
+
+	scryptN      = 32768
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+
+	saltSize  = 16
+	nonceSize = 12
+
+	// envelopeOverhead is the number of bytes SetTagBytesEncrypted adds
+	// on top of the plaintext: version + salt + nonce + GCM tag.
+	envelopeOverhead = 1 + saltSize + nonceSize + 16
+)
+
+// SetTagBytesEncrypted wraps val in an encrypted envelope using a key
+// derived from passphrase via scrypt, then stores the envelope under tag
+// the same way SetTagBytes does. The DataLength budget check happens
+// against the full envelope, so envelopeOverhead is already accounted
+// for.
+func (a *ADV) SetTagBytesEncrypted(tag uint8, val []byte, passphrase []byte) bool {
+	envelope, err := EncryptEnvelope(val, passphrase)
+	if err != nil {
+		return false
+	}
+
+	return a.SetTagBytes(tag, envelope)
+}
+
+// EncryptEnvelope seals val the same way SetTagBytesEncrypted does,
+// without storing it anywhere. It lets callers that write through a
+// MetaBackend (rather than an in-memory ADV) still encrypt a value
+// before handing it to WriteTag.
+func EncryptEnvelope(val, passphrase []byte) ([]byte, error) {
+	return sealEnvelope(val, passphrase)
+}
+
+// GetTagBytesDecrypted returns tag's value after transparently decrypting
+// the envelope written by SetTagBytesEncrypted.
+func (a *ADV) GetTagBytesDecrypted(tag uint8, passphrase []byte) ([]byte, error) {
+	envelope, ok := a.GetTagBytes(tag)
+	if !ok {
+		return nil, fmt.Errorf("adv: tag %d not found", tag)
+	}
+
+	if len(envelope) == 0 {
+		return nil, fmt.Errorf("adv: tag %d is empty, not an encrypted envelope", tag)
+	}
+
+	switch envelope[0] {
+	case encryptionVersion1:
+		return openEnvelopeV1(envelope, passphrase)
+	default:
+		return nil, fmt.Errorf("adv: tag %d has unrecognised envelope version %#x", tag, envelope[0])
+	}
+}
+
+func sealEnvelope(val, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+
+	gcm, err := gcmFor(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, nonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+
+	ciphertext := gcm.Seal(nil, nonce, val, nil)
+
+	envelope := make([]byte, 0, envelopeOverhead+len(val))
+	envelope = append(envelope, encryptionVersion1)
+	envelope = append(envelope, salt...)
+	envelope = append(envelope, nonce...)
+	envelope = append(envelope, ciphertext...)
+	return envelope, nil
+}
+
+func openEnvelopeV1(envelope, passphrase []byte) ([]byte, error) {
+	headerLen := 1 + saltSize + nonceSize
+	if len(envelope) < headerLen {
+		return nil, fmt.Errorf("adv: encrypted envelope truncated")
+	}
+
+	salt := envelope[1 : 1+saltSize]
+	nonce := envelope[1+saltSize : headerLen]
+	ciphertext := envelope[headerLen:]
+
+	gcm, err := gcmFor(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("adv: decrypting tag value: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func gcmFor(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("initialising cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSetGetTagBytesEncrypted_RoundTrip(t *testing.T) {
+	a := &ADV{Tags: make(map[uint8][]byte)}
+	passphrase := []byte("correct horse battery staple")
+
+	if !a.SetTagBytesEncrypted(1, []byte("top secret config"), passphrase) {
+		t.Fatalf("SetTagBytesEncrypted reported not enough space")
+	}
+
+	got, err := a.GetTagBytesDecrypted(1, passphrase)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, []byte("top secret config")) {
+		t.Fatalf("unexpected plaintext: %q", got)
+	}
+}
+
+func TestGetTagBytesDecrypted_WrongPassphrase(t *testing.T) {
+	a := &ADV{Tags: make(map[uint8][]byte)}
+	if !a.SetTagBytesEncrypted(1, []byte("top secret config"), []byte("right passphrase")) {
+		t.Fatalf("SetTagBytesEncrypted reported not enough space")
+	}
+
+	if _, err := a.GetTagBytesDecrypted(1, []byte("wrong passphrase")); err == nil {
+		t.Fatalf("expected an error decrypting with the wrong passphrase")
+	}
+}
+
+func TestGetTagBytesDecrypted_PlaintextTag(t *testing.T) {
+	a := &ADV{Tags: make(map[uint8][]byte)}
+	if !a.SetTagBytes(1, []byte("not encrypted")) {
+		t.Fatalf("SetTagBytes reported not enough space")
+	}
+
+	if _, err := a.GetTagBytesDecrypted(1, []byte("whatever")); err == nil {
+		t.Fatalf("expected an error decrypting a plaintext tag")
+	}
+}
+
+func TestSetTagBytesEncrypted_AccountsForEnvelopeOverhead(t *testing.T) {
+	a := &ADV{Tags: make(map[uint8][]byte)}
+	val := make([]byte, DataLength-envelopeOverhead+1)
+
+	if a.SetTagBytesEncrypted(1, val, []byte("passphrase")) {
+		t.Fatalf("expected SetTagBytesEncrypted to reject a value that overflows DataLength once the envelope is added")
+	}
+}
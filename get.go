@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aenix-io/talos-meta-tool/tags"
+)
+
+// runGet prints a single tag's raw value to stdout, e.g. for piping into
+// `talosctl apply-config`.
+func runGet(args []string) error {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	devicePath, node, talosconfig := registerBackendFlags(fs)
+	tag := fs.String("tag", "", "Tag to read, by symbolic name (e.g. user-reserved-1) or numeric id")
+	raw := fs.Bool("raw", false, "Print the tag value as hex instead of raw bytes")
+	decrypt := fs.Bool("decrypt", false, "Decrypt the tag value with a passphrase-derived key")
+	passphraseFile := fs.String("passphrase-file", "", "Path to a file containing the passphrase (prompted on the TTY if omitted)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *tag == "" {
+		return fmt.Errorf("usage: get (-device <META-device> | -node <addr>) -tag <name|id> [--raw] [--decrypt]")
+	}
+
+	tagID, err := tags.Parse(*tag)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	backend, err := openBackend(ctx, *devicePath, *node, *talosconfig)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	adv, err := backend.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("error loading ADV: %w", err)
+	}
+
+	var value []byte
+	if *decrypt {
+		passphrase, err := resolvePassphrase(*passphraseFile)
+		if err != nil {
+			return fmt.Errorf("error resolving passphrase: %w", err)
+		}
+
+		value, err = adv.GetTagBytesDecrypted(tagID, passphrase)
+		if err != nil {
+			return fmt.Errorf("error decrypting tag %d: %w", tagID, err)
+		}
+	} else {
+		var ok bool
+		value, ok = adv.GetTagBytes(tagID)
+		if !ok {
+			return fmt.Errorf("tag %d not found", tagID)
+		}
+	}
+
+	if *raw {
+		fmt.Println(hex.EncodeToString(value))
+		return nil
+	}
+
+	_, err = os.Stdout.Write(value)
+	return err
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// resolvePassphrase returns the passphrase to use for tag encryption or
+// decryption. It reads passphraseFile if one was given, otherwise it
+// prompts interactively on the controlling TTY with echo disabled.
+func resolvePassphrase(passphraseFile string) ([]byte, error) {
+	if passphraseFile != "" {
+		data, err := ioutil.ReadFile(passphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading passphrase file: %w", err)
+		}
+
+		return []byte(strings.TrimRight(string(data), "\r\n")), nil
+	}
+
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("reading passphrase from terminal: %w", err)
+	}
+
+	return passphrase, nil
+}
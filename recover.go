@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// SkippedTag records a tag that could not be fully salvaged from a
+// corrupted slot during recovery.
+type SkippedTag struct {
+	Slot   string // "primary" or "backup"
+	Tag    uint8
+	Offset int
+	Reason string
+}
+
+// RecoveryReport describes what LoadWithFallback found in each of the two
+// on-disk copies and which one(s) it used to produce the returned ADV.
+type RecoveryReport struct {
+	PrimaryValid bool
+	BackupValid  bool
+
+	// UsedSlot is one of "primary", "backup" or "reconciled".
+	UsedSlot string
+
+	// Agree reports whether the primary and backup tag sets matched.
+	// Only meaningful when both PrimaryValid and BackupValid are true:
+	// a crash between WriteToDisk's two sequential writes can leave both
+	// slots independently valid (good magic and checksum) yet holding
+	// different tags, with the primary being the freshly written one.
+	Agree bool
+
+	PrimaryError string
+	BackupError  string
+
+	// Skipped lists tags that were dropped or truncated while salvaging
+	// a slot that failed full validation. Only populated when UsedSlot
+	// is "reconciled".
+	Skipped []SkippedTag
+}
+
+// LoadWithFallback reads both 256 KiB copies of the ADV from r (the
+// primary at offset 0, the backup at offset Length) and validates each
+// independently. If both copies agree, it returns that ADV. If only one
+// validates, it returns that one and reports which slot was bad. If
+// neither validates, it salvages whatever tag|size|value TLV runs it can
+// from both slots and returns a partial, reconciled ADV.
+func (a *ADV) LoadWithFallback(r io.ReaderAt) (*ADV, RecoveryReport, error) {
+	primaryBuf := make([]byte, Length)
+	if _, err := r.ReadAt(primaryBuf, 0); err != nil && err != io.EOF {
+		return nil, RecoveryReport{}, fmt.Errorf("adv: reading primary slot: %w", err)
+	}
+
+	backupBuf := make([]byte, Length)
+	if _, err := r.ReadAt(backupBuf, Length); err != nil && err != io.EOF {
+		return nil, RecoveryReport{}, fmt.Errorf("adv: reading backup slot: %w", err)
+	}
+
+	primaryTags, primaryErr := parseSlot(primaryBuf)
+	backupTags, backupErr := parseSlot(backupBuf)
+
+	report := RecoveryReport{
+		PrimaryValid: primaryErr == nil,
+		BackupValid:  backupErr == nil,
+	}
+
+	switch {
+	case primaryErr == nil && backupErr == nil:
+		report.UsedSlot = "primary"
+		report.Agree = tagsEqual(primaryTags, backupTags)
+		return &ADV{Tags: primaryTags}, report, nil
+
+	case primaryErr == nil:
+		report.UsedSlot = "primary"
+		report.BackupError = backupErr.Error()
+		return &ADV{Tags: primaryTags}, report, nil
+
+	case backupErr == nil:
+		report.UsedSlot = "backup"
+		report.PrimaryError = primaryErr.Error()
+		return &ADV{Tags: backupTags}, report, nil
+
+	default:
+		report.UsedSlot = "reconciled"
+		report.PrimaryError = primaryErr.Error()
+		report.BackupError = backupErr.Error()
+
+		salvagedPrimary, primarySkipped := salvageSlot("primary", primaryBuf)
+		salvagedBackup, backupSkipped := salvageSlot("backup", backupBuf)
+		report.Skipped = append(primarySkipped, backupSkipped...)
+
+		tags := make(map[uint8][]byte)
+		for tag, value := range salvagedBackup {
+			tags[tag] = value
+		}
+		for tag, value := range salvagedPrimary {
+			tags[tag] = value
+		}
+
+		return &ADV{Tags: tags}, report, nil
+	}
+}
+
+// tagsEqual reports whether a and b hold the same set of tags with the
+// same byte-for-byte values.
+func tagsEqual(a, b map[uint8][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for tag, value := range a {
+		other, ok := b[tag]
+		if !ok || !bytes.Equal(value, other) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// salvageSlot scans a corrupted slot for tag|size|value TLV runs between
+// the magic markers, stopping at the recorded tag count (so it doesn't
+// walk into the zero-padding after the real tags and decode it as a
+// phantom tag 0) or at the first declared size that would overflow the
+// remaining buffer, whichever comes first. It returns whatever tags it
+// could recover and a record of what it had to give up on.
+func salvageSlot(slot string, buf []byte) (map[uint8][]byte, []SkippedTag) {
+	tags := make(map[uint8][]byte)
+	var skipped []SkippedTag
+
+	if len(buf) < 44 {
+		return tags, skipped
+	}
+
+	count := binary.BigEndian.Uint32(buf[4:8])
+
+	data := buf[8 : len(buf)-36]
+	offset := 8
+	for i := uint32(0); i < count && len(data) >= 8; i++ {
+		tag := uint8(binary.BigEndian.Uint32(data[0:4]))
+		size := binary.BigEndian.Uint32(data[4:8])
+
+		if len(data) < int(size)+8 {
+			skipped = append(skipped, SkippedTag{
+				Slot:   slot,
+				Tag:    tag,
+				Offset: offset,
+				Reason: fmt.Sprintf("declared size %d at offset %d exceeds remaining %d bytes", size, offset, len(data)-8),
+			})
+			break
+		}
+
+		tags[tag] = data[8 : 8+size]
+		data = data[8+size:]
+		offset += 8 + int(size)
+	}
+
+	return tags, skipped
+}
+
+// runRecover repairs a META device whose primary and backup copies have
+// diverged or been corrupted, using LoadWithFallback to reconcile them.
+func runRecover(args []string) error {
+	fs := flag.NewFlagSet("recover", flag.ExitOnError)
+	devicePath := fs.String("device", "", "Path to the META device (e.g., /dev/sda4)")
+	dryRun := fs.Bool("dry-run", false, "Report what would be recovered without writing to disk")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *devicePath == "" {
+		return fmt.Errorf("usage: recover -device <META-device> [--dry-run]")
+	}
+
+	f, err := openReadWrite(*devicePath)
+	if err != nil {
+		return fmt.Errorf("error opening device: %w", err)
+	}
+	defer f.Close()
+
+	a := &ADV{}
+	recovered, report, err := a.LoadWithFallback(f)
+	if err != nil {
+		return fmt.Errorf("error recovering ADV: %w", err)
+	}
+
+	printRecoveryReport(report)
+
+	if report.PrimaryValid && report.BackupValid {
+		if report.Agree {
+			fmt.Println("Both copies are valid and agree; nothing to do.")
+			return nil
+		}
+
+		fmt.Println("Both copies are individually valid but disagree; the primary (written first, so the more recent copy after a partial write) will be used to resync the backup.")
+	}
+
+	if *dryRun {
+		fmt.Println("Dry run: not writing anything to disk.")
+		return nil
+	}
+
+	if err := recovered.WriteToDisk(*devicePath); err != nil {
+		return fmt.Errorf("error writing recovered ADV to disk: %w", err)
+	}
+
+	fmt.Println("Recovered ADV written to both copies.")
+	return nil
+}
+
+func printRecoveryReport(report RecoveryReport) {
+	fmt.Printf("primary valid: %v, backup valid: %v, used: %s\n", report.PrimaryValid, report.BackupValid, report.UsedSlot)
+	if report.PrimaryValid && report.BackupValid {
+		fmt.Printf("copies agree: %v\n", report.Agree)
+	}
+	if report.PrimaryError != "" {
+		fmt.Printf("primary error: %s\n", report.PrimaryError)
+	}
+	if report.BackupError != "" {
+		fmt.Printf("backup error: %s\n", report.BackupError)
+	}
+	for _, s := range report.Skipped {
+		fmt.Printf("skipped tag %d in %s slot at offset %d: %s\n", s.Tag, s.Slot, s.Offset, s.Reason)
+	}
+}
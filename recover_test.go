@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// buildSlot assembles a single valid 256 KiB ADV slot containing tags.
+func buildSlot(t *testing.T, tags map[uint8][]byte) []byte {
+	t.Helper()
+
+	a := &ADV{Tags: tags}
+	buf, err := a.marshal()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return buf
+}
+
+// device is an in-memory io.ReaderAt standing in for a META block device
+// with a primary slot followed by a backup slot.
+type device struct {
+	data []byte
+}
+
+func (d *device) ReadAt(p []byte, off int64) (int, error) {
+	n := copy(p, d.data[off:])
+	return n, nil
+}
+
+func newDevice(primary, backup []byte) *device {
+	data := make([]byte, 2*Length)
+	copy(data[:Length], primary)
+	copy(data[Length:], backup)
+	return &device{data: data}
+}
+
+func TestLoadWithFallback_BothValid(t *testing.T) {
+	slot := buildSlot(t, map[uint8][]byte{1: []byte("hello")})
+	dev := newDevice(slot, slot)
+
+	adv, report, err := (&ADV{}).LoadWithFallback(dev)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.PrimaryValid || !report.BackupValid || report.UsedSlot != "primary" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if string(adv.Tags[1]) != "hello" {
+		t.Fatalf("unexpected tag value: %q", adv.Tags[1])
+	}
+}
+
+func TestLoadWithFallback_BothValidButDisagree(t *testing.T) {
+	primary := buildSlot(t, map[uint8][]byte{1: []byte("fresh")})
+	backup := buildSlot(t, map[uint8][]byte{1: []byte("stale")})
+	dev := newDevice(primary, backup)
+
+	adv, report, err := (&ADV{}).LoadWithFallback(dev)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.PrimaryValid || !report.BackupValid {
+		t.Fatalf("expected both slots to validate, got: %+v", report)
+	}
+	if report.Agree {
+		t.Fatalf("expected report.Agree to be false for differing slots, got: %+v", report)
+	}
+	if report.UsedSlot != "primary" {
+		t.Fatalf("expected the primary slot to be preferred, got: %+v", report)
+	}
+	if string(adv.Tags[1]) != "fresh" {
+		t.Fatalf("unexpected tag value: %q", adv.Tags[1])
+	}
+}
+
+func TestLoadWithFallback_TornPrimary(t *testing.T) {
+	good := buildSlot(t, map[uint8][]byte{1: []byte("hello")})
+	torn := make([]byte, Length) // all zero: no magic markers at all
+
+	dev := newDevice(torn, good)
+
+	adv, report, err := (&ADV{}).LoadWithFallback(dev)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.PrimaryValid || !report.BackupValid || report.UsedSlot != "backup" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if string(adv.Tags[1]) != "hello" {
+		t.Fatalf("unexpected tag value: %q", adv.Tags[1])
+	}
+}
+
+func TestLoadWithFallback_BitFlipInChecksum(t *testing.T) {
+	good := buildSlot(t, map[uint8][]byte{1: []byte("hello")})
+	flipped := make([]byte, len(good))
+	copy(flipped, good)
+	flipped[len(flipped)-10] ^= 0xff // corrupt a byte inside the checksum
+
+	dev := newDevice(flipped, good)
+
+	adv, report, err := (&ADV{}).LoadWithFallback(dev)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.PrimaryValid || !report.BackupValid || report.UsedSlot != "backup" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if string(adv.Tags[1]) != "hello" {
+		t.Fatalf("unexpected tag value: %q", adv.Tags[1])
+	}
+}
+
+func TestLoadWithFallback_BothCorruptTruncatedTLV(t *testing.T) {
+	good := buildSlot(t, map[uint8][]byte{1: []byte("hello"), 2: []byte("world")})
+
+	// Corrupt the primary's checksum so it fails validation, but leave its
+	// TLV data intact and salvageable.
+	primary := make([]byte, len(good))
+	copy(primary, good)
+	primary[len(primary)-10] ^= 0xff
+
+	// Build a backup whose only entry, tag 2, declares a size larger than
+	// the remaining buffer, simulating a truncated write, and whose checksum
+	// is also corrupted so neither slot validates cleanly.
+	backup := make([]byte, Length)
+	binary.BigEndian.PutUint32(backup[0:4], Magic1)
+	binary.BigEndian.PutUint32(backup[4:8], 1)
+	binary.BigEndian.PutUint32(backup[len(backup)-4:], Magic2)
+	data := backup[8 : len(backup)-36]
+	binary.BigEndian.PutUint32(data[0:4], 2)
+	binary.BigEndian.PutUint32(data[4:8], uint32(len(data)-8+1)) // one byte too many
+	copy(backup[len(backup)-36:len(backup)-4], []byte("not a valid checksum, deliberately"))
+
+	dev := newDevice(primary, backup)
+
+	adv, report, err := (&ADV{}).LoadWithFallback(dev)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.PrimaryValid || report.BackupValid || report.UsedSlot != "reconciled" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if string(adv.Tags[1]) != "hello" || string(adv.Tags[2]) != "world" {
+		t.Fatalf("expected tags salvaged from the primary slot, got: %+v", adv.Tags)
+	}
+
+	found := false
+	for _, s := range report.Skipped {
+		if s.Slot == "backup" && s.Tag == 2 {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected a skipped-tag record for the truncated backup tag, got: %+v", report.Skipped)
+	}
+}
+
+func TestSalvageSlot_StopsAtOverflowingSize(t *testing.T) {
+	buf := make([]byte, Length)
+	binary.BigEndian.PutUint32(buf[4:8], 1)
+	data := buf[8 : len(buf)-36]
+	binary.BigEndian.PutUint32(data[0:4], 3)
+	binary.BigEndian.PutUint32(data[4:8], uint32(len(data)))
+
+	tags, skipped := salvageSlot("primary", buf)
+	if len(tags) != 0 {
+		t.Fatalf("expected no tags salvaged, got: %+v", tags)
+	}
+	if len(skipped) != 1 || skipped[0].Tag != 3 {
+		t.Fatalf("expected one skipped tag 3, got: %+v", skipped)
+	}
+}
+
+// TestSalvageSlot_StopsAtTagCount ensures salvageSlot relies on the
+// recorded tag count rather than scanning until the buffer runs out, so
+// it doesn't decode the zero-padding after the real tags as a phantom
+// tag 0.
+func TestSalvageSlot_StopsAtTagCount(t *testing.T) {
+	buf := make([]byte, Length)
+	binary.BigEndian.PutUint32(buf[4:8], 1)
+	data := buf[8 : len(buf)-36]
+	binary.BigEndian.PutUint32(data[0:4], 5)
+	binary.BigEndian.PutUint32(data[4:8], 5)
+	copy(data[8:13], "hello")
+
+	tags, skipped := salvageSlot("primary", buf)
+	if len(skipped) != 0 {
+		t.Fatalf("expected nothing skipped, got: %+v", skipped)
+	}
+	if len(tags) != 1 || string(tags[5]) != "hello" {
+		t.Fatalf("expected only tag 5 to be salvaged, got: %+v", tags)
+	}
+}
+
+func TestParseSlot_DoesNotMutateInput(t *testing.T) {
+	good := buildSlot(t, map[uint8][]byte{1: []byte("hello")})
+	original := make([]byte, len(good))
+	copy(original, good)
+
+	if _, err := parseSlot(good); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(good, original) {
+		t.Fatalf("parseSlot mutated its input buffer")
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	"github.com/siderolabs/talos/pkg/machinery/api/machine"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	runtimeres "github.com/siderolabs/talos/pkg/machinery/resources/runtime"
+)
+
+// ResourceBackend implements MetaBackend against a running Talos node's
+// machined, over its gRPC resource API, instead of a raw block device.
+// Reads go through the runtime.MetaKey/MetaKeys resources; writes and
+// deletes go through the dedicated MetaWrite/MetaDelete RPCs that back
+// `talosctl meta`.
+type ResourceBackend struct {
+	Client   *client.Client
+	Endpoint string
+}
+
+// NewResourceBackend dials endpoint using the client credentials from
+// talosconfigPath, the same file `talosctl` itself reads.
+func NewResourceBackend(ctx context.Context, endpoint, talosconfigPath string) (*ResourceBackend, error) {
+	c, err := client.New(ctx,
+		client.WithConfigFromFile(talosconfigPath),
+		client.WithEndpoints(endpoint),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", endpoint, err)
+	}
+
+	return &ResourceBackend{Client: c, Endpoint: endpoint}, nil
+}
+
+// Close releases the underlying gRPC connection.
+func (b *ResourceBackend) Close() error {
+	return b.Client.Close()
+}
+
+func (b *ResourceBackend) Load(ctx context.Context) (*ADV, error) {
+	list, err := b.Client.COSI.List(ctx, resource.NewMetadata(runtimeres.NamespaceName, runtimeres.MetaKeyType, "", resource.VersionUndefined))
+	if err != nil {
+		return nil, fmt.Errorf("listing MetaKeys: %w", err)
+	}
+
+	a := &ADV{Tags: make(map[uint8][]byte)}
+	for _, item := range list.Items {
+		metaKey, ok := item.(*runtimeres.MetaKey)
+		if !ok {
+			continue
+		}
+
+		tag, err := strconv.ParseUint(item.Metadata().ID(), 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("parsing MetaKey id %q: %w", item.Metadata().ID(), err)
+		}
+
+		a.Tags[uint8(tag)] = []byte(metaKey.TypedSpec().Value)
+	}
+
+	return a, nil
+}
+
+func (b *ResourceBackend) WriteTag(ctx context.Context, tag uint8, val []byte) error {
+	_, err := b.Client.MachineClient.MetaWrite(ctx, &machine.MetaWriteRequest{
+		Key:   uint32(tag),
+		Value: val,
+	})
+	if err != nil {
+		return fmt.Errorf("writing tag %d via MetaWrite: %w", tag, err)
+	}
+
+	return nil
+}
+
+func (b *ResourceBackend) DeleteTag(ctx context.Context, tag uint8) error {
+	_, err := b.Client.MachineClient.MetaDelete(ctx, &machine.MetaDeleteRequest{
+		Key: uint32(tag),
+	})
+	if err != nil {
+		return fmt.Errorf("deleting tag %d via MetaDelete: %w", tag, err)
+	}
+
+	return nil
+}
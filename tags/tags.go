@@ -0,0 +1,94 @@
+// Package tags gives symbolic names to the META tag ids that upstream
+// Talos itself reads and writes, so the CLI can refer to them as e.g.
+// "user-reserved-1" instead of a bare magic number, and so writes to
+// tags Talos manages can be guarded behind -force.
+package tags
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Tag ids currently documented by upstream Talos's META partition
+// format (pkg/machinery/resources/runtime).
+const (
+	Upgrade                     = 1
+	StagedUpgradeImageRef       = 2
+	StagedUpgradeInstallOptions = 3
+	PlatformNetworkConfig       = 4
+	UserReserved1               = 0x0a
+	UserReserved2               = 0x0b
+	UserReserved3               = 0x0c
+	UserReserved4               = 0x0d
+	UserReserved5               = 0x0e
+	StateEncryptionConfig       = 0x0f
+)
+
+// entry describes one documented tag: its symbolic name, and whether it
+// is managed by Talos itself rather than free for operator use.
+type entry struct {
+	name     string
+	reserved bool
+}
+
+var byID = map[uint8]entry{
+	Upgrade:                     {"upgrade", true},
+	StagedUpgradeImageRef:       {"staged-upgrade-image-ref", true},
+	StagedUpgradeInstallOptions: {"staged-upgrade-install-options", true},
+	PlatformNetworkConfig:       {"platform-network-config", true},
+	UserReserved1:               {"user-reserved-1", false},
+	UserReserved2:               {"user-reserved-2", false},
+	UserReserved3:               {"user-reserved-3", false},
+	UserReserved4:               {"user-reserved-4", false},
+	UserReserved5:               {"user-reserved-5", false},
+	StateEncryptionConfig:       {"state-encryption-config", true},
+}
+
+var byName = func() map[string]uint8 {
+	m := make(map[string]uint8, len(byID))
+	for id, e := range byID {
+		m[e.name] = id
+	}
+	return m
+}()
+
+// Name returns id's symbolic name, or "" if id is not a documented tag.
+func Name(id uint8) string {
+	return byID[id].name
+}
+
+// Reserved reports whether id is managed by Talos itself, or isn't a
+// documented tag at all. Writing to such a tag requires -force.
+func Reserved(id uint8) bool {
+	e, ok := byID[id]
+	return !ok || e.reserved
+}
+
+// IsMachineConfigTag reports whether id is one of the user-reserved tags
+// conventionally used to hold a Talos machine config YAML document, as
+// opposed to a tag Talos itself manages (upgrade state, encryption
+// config, platform network config, ...) whose payload isn't a machine
+// config at all.
+func IsMachineConfigTag(id uint8) bool {
+	switch id {
+	case UserReserved1, UserReserved2, UserReserved3, UserReserved4, UserReserved5:
+		return true
+	default:
+		return false
+	}
+}
+
+// Parse resolves s to a numeric tag id. s may be a symbolic name (e.g.
+// "user-reserved-1") or a decimal id.
+func Parse(s string) (uint8, error) {
+	if id, ok := byName[s]; ok {
+		return id, nil
+	}
+
+	n, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("tags: %q is not a known tag name or a valid tag id", s)
+	}
+
+	return uint8(n), nil
+}
@@ -0,0 +1,55 @@
+package tags
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		in   string
+		want uint8
+	}{
+		{"user-reserved-1", UserReserved1},
+		{"upgrade", Upgrade},
+		{"10", UserReserved1},
+	}
+
+	for _, c := range cases {
+		got, err := Parse(c.in)
+		if err != nil {
+			t.Errorf("Parse(%q): unexpected error: %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("Parse(%q) = %d, want %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParse_Unknown(t *testing.T) {
+	if _, err := Parse("not-a-tag"); err == nil {
+		t.Fatalf("expected an error for an unrecognised tag name")
+	}
+}
+
+func TestIsMachineConfigTag(t *testing.T) {
+	if !IsMachineConfigTag(UserReserved1) {
+		t.Errorf("UserReserved1 should be a machine-config tag")
+	}
+	if IsMachineConfigTag(Upgrade) {
+		t.Errorf("Upgrade should not be a machine-config tag")
+	}
+	if IsMachineConfigTag(StateEncryptionConfig) {
+		t.Errorf("StateEncryptionConfig should not be a machine-config tag")
+	}
+}
+
+func TestReserved(t *testing.T) {
+	if Reserved(UserReserved1) {
+		t.Errorf("UserReserved1 should not be reserved")
+	}
+	if !Reserved(Upgrade) {
+		t.Errorf("Upgrade should be reserved")
+	}
+	if !Reserved(0xff) {
+		t.Errorf("an undocumented tag should be treated as reserved")
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/siderolabs/talos/pkg/machinery/config/configloader"
+)
+
+// validateMachineConfig parses configData as a typed Talos machine config
+// and runs Talos's own structural validation against it for runtime mode
+// mode, catching problems a plain YAML round-trip can't: missing
+// required fields, options that don't apply to the target runtime, and
+// so on. A config that fails this check would have bricked the next
+// boot had it been written to META.
+func validateMachineConfig(configData []byte, mode string) error {
+	runtimeMode, err := parseRuntimeMode(mode)
+	if err != nil {
+		return err
+	}
+
+	provider, err := configloader.NewFromBytes(configData)
+	if err != nil {
+		return fmt.Errorf("parsing machine config: %w", err)
+	}
+
+	warnings, err := provider.Validate(runtimeMode)
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "talos-meta-tool: config warning: %s\n", w)
+	}
+	if err != nil {
+		return fmt.Errorf("machine config failed validation for runtime mode %q: %w", mode, err)
+	}
+
+	return nil
+}
+
+// cliRuntimeMode adapts the -mode flag to config.RuntimeMode, the
+// interface (config.Provider).Validate expects.
+type cliRuntimeMode string
+
+func (m cliRuntimeMode) String() string        { return string(m) }
+func (m cliRuntimeMode) RequiresInstall() bool { return m == "metal" }
+func (m cliRuntimeMode) InContainer() bool     { return m == "container" }
+
+func parseRuntimeMode(mode string) (cliRuntimeMode, error) {
+	switch mode {
+	case "metal", "container", "cloud":
+		return cliRuntimeMode(mode), nil
+	default:
+		return "", fmt.Errorf("unknown -mode %q, want one of: metal, container, cloud", mode)
+	}
+}
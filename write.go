@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/aenix-io/talos-meta-tool/tags"
+)
+
+// runWrite validates a machine config and writes it into META, either on
+// an offline device or a running Talos node.
+func runWrite(args []string) error {
+	fs := flag.NewFlagSet("write", flag.ExitOnError)
+	devicePath, node, talosconfig := registerBackendFlags(fs)
+	configPath := fs.String("config", "", "Path to the configuration file (e.g., config.yaml)")
+	tag := fs.String("tag", "user-reserved-1", "Tag to write, by symbolic name (e.g. user-reserved-1) or numeric id")
+	mode := fs.String("mode", "metal", "Runtime mode to validate the config against: metal, container, or cloud")
+	force := fs.Bool("force", false, "Allow writing to a reserved or undocumented tag")
+	encrypt := fs.Bool("encrypt", false, "Encrypt the configuration at rest with a passphrase-derived key")
+	passphraseFile := fs.String("passphrase-file", "", "Path to a file containing the passphrase (prompted on the TTY if omitted)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *configPath == "" {
+		return fmt.Errorf("usage: write (-device <META-device> | -node <addr>) -config <path to config file> [-tag <name|id>]")
+	}
+
+	tagID, err := tags.Parse(*tag)
+	if err != nil {
+		return err
+	}
+
+	if tags.Reserved(tagID) && !*force {
+		return fmt.Errorf("tag %d is reserved for Talos's own use; pass -force to write it anyway", tagID)
+	}
+
+	configData, err := ioutil.ReadFile(*configPath)
+	if err != nil {
+		return fmt.Errorf("error reading configuration file: %w", err)
+	}
+
+	// YAML validation
+	var config interface{}
+	if err := yaml.Unmarshal(configData, &config); err != nil {
+		return fmt.Errorf("invalid YAML configuration: %w", err)
+	}
+
+	// Marshaling back to ensure correct format
+	validatedConfigData, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("error marshaling YAML configuration: %w", err)
+	}
+
+	if tags.IsMachineConfigTag(tagID) {
+		if err := validateMachineConfig(validatedConfigData, *mode); err != nil {
+			return err
+		}
+	}
+
+	if *encrypt {
+		passphrase, err := resolvePassphrase(*passphraseFile)
+		if err != nil {
+			return fmt.Errorf("error resolving passphrase: %w", err)
+		}
+
+		validatedConfigData, err = EncryptEnvelope(validatedConfigData, passphrase)
+		if err != nil {
+			return fmt.Errorf("error encrypting configuration: %w", err)
+		}
+	}
+
+	ctx := context.Background()
+
+	backend, err := openBackend(ctx, *devicePath, *node, *talosconfig)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	if err := backend.WriteTag(ctx, tagID, validatedConfigData); err != nil {
+		return fmt.Errorf("error writing configuration: %w", err)
+	}
+
+	fmt.Println("Configuration successfully validated and written to META partition.")
+	return nil
+}